@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+)
+
+// AnnounceMode selects how the gratuitous ARP / unsolicited IPv6 Neighbor
+// Advertisement is sent after a failover attaches the EIP to a new target.
+type AnnounceMode string
+
+const (
+	// AnnounceModeNone disables the post-attach announcement entirely.
+	AnnounceModeNone AnnounceMode = "none"
+	// AnnounceModeLocal sends the announcement from a raw socket opened on
+	// this host, which must therefore be running on the target instance.
+	AnnounceModeLocal AnnounceMode = "local"
+	// AnnounceModeRemote SSHes to the target and runs arping/ndsend there,
+	// for operators who run ha-flow off-box.
+	AnnounceModeRemote AnnounceMode = "remote"
+)
+
+// AnnounceConfig configures the post-attach announcement hook.
+type AnnounceConfig struct {
+	Mode AnnounceMode
+
+	// Iface is the network interface to announce on, e.g. "eth0": this
+	// host's interface in local mode, and the interface name as seen by the
+	// target's own OS in remote mode, where it is passed to arping/ndsend.
+	Iface string
+
+	Remote RemoteAnnounceConfig
+}
+
+// AnnounceHook broadcasts a gratuitous ARP reply (and, if applicable, an
+// unsolicited IPv6 Neighbor Advertisement) for eip on behalf of target, so
+// upstream routers and neighbours refresh their ARP/NDP caches instead of
+// blackholing traffic until the old mapping expires.
+type AnnounceHook interface {
+	Announce(ctx context.Context, target Target, eip string, eipv6 string) error
+}
+
+// noopAnnounceHook is used when announcements are disabled.
+type noopAnnounceHook struct{}
+
+func (noopAnnounceHook) Announce(ctx context.Context, target Target, eip string, eipv6 string) error {
+	return nil
+}
+
+var (
+	flagAnnounceMode       = "none"
+	flagAnnounceIface      = ""
+	flagAnnounceEIPv6      = ""
+	flagAnnounceRemoteUser       = ""
+	flagAnnounceRemotePort       = 22
+	flagAnnounceRemoteKey        = ""
+	flagAnnounceRemoteKnownHosts = ""
+)
+
+func registerAnnounceFlags() {
+	flag.StringVar(&flagAnnounceMode, "announce-mode", "none", "post-attach ARP/NDP announcement mode (none, local, remote)")
+	flag.StringVar(&flagAnnounceIface, "announce-iface", "", "network interface to announce on (local mode: this host's; remote mode: the target's)")
+	flag.StringVar(&flagAnnounceEIPv6, "announce-eipv6", "", "IPv6 address of the EIP, if it has one, to also send an unsolicited neighbor advertisement for")
+	flag.StringVar(&flagAnnounceRemoteUser, "announce-remote-user", "root", "SSH user for remote announce mode")
+	flag.IntVar(&flagAnnounceRemotePort, "announce-remote-port", 22, "SSH port for remote announce mode")
+	flag.StringVar(&flagAnnounceRemoteKey, "announce-remote-key", "", "SSH private key path for remote announce mode")
+	flag.StringVar(&flagAnnounceRemoteKnownHosts, "announce-remote-known-hosts", "", "OpenSSH known_hosts file used to verify the target's host key in remote announce mode (required)")
+}
+
+func announceConfigFromFlags() AnnounceConfig {
+	return AnnounceConfig{
+		Mode:  AnnounceMode(flagAnnounceMode),
+		Iface: flagAnnounceIface,
+		Remote: RemoteAnnounceConfig{
+			User:           flagAnnounceRemoteUser,
+			Port:           flagAnnounceRemotePort,
+			PrivateKeyPath: flagAnnounceRemoteKey,
+			KnownHostsPath: flagAnnounceRemoteKnownHosts,
+			Iface:          flagAnnounceIface,
+			Timeout:        10 * time.Second,
+		},
+	}
+}
+
+// AnnounceConfigYAML is the YAML representation of AnnounceConfig.
+type AnnounceConfigYAML struct {
+	Mode  AnnounceMode `yaml:"mode"`
+	Iface string       `yaml:"iface,omitempty"`
+
+	Remote struct {
+		User           string   `yaml:"user"`
+		Port           int      `yaml:"port"`
+		PrivateKeyPath string   `yaml:"private_key_path"`
+		KnownHostsPath string   `yaml:"known_hosts_path"`
+		Timeout        Duration `yaml:"timeout,omitempty"`
+	} `yaml:"remote,omitempty"`
+}
+
+func (a *AnnounceConfigYAML) toAnnounceConfig() AnnounceConfig {
+	if a == nil {
+		return AnnounceConfig{Mode: AnnounceModeNone}
+	}
+
+	return AnnounceConfig{
+		Mode:  a.Mode,
+		Iface: a.Iface,
+		Remote: RemoteAnnounceConfig{
+			User:           a.Remote.User,
+			Port:           a.Remote.Port,
+			PrivateKeyPath: a.Remote.PrivateKeyPath,
+			KnownHostsPath: a.Remote.KnownHostsPath,
+			Iface:          a.Iface,
+			Timeout:        a.Remote.Timeout.Duration(),
+		},
+	}
+}
+
+func newAnnounceHook(cfg AnnounceConfig) (AnnounceHook, error) {
+	switch cfg.Mode {
+	case "", AnnounceModeNone:
+		return noopAnnounceHook{}, nil
+	case AnnounceModeLocal:
+		return NewLocalAnnounceHook(cfg.Iface), nil
+	case AnnounceModeRemote:
+		return NewRemoteAnnounceHook(cfg.Remote)
+	default:
+		return nil, fmt.Errorf("unknown announce mode: %v", cfg.Mode)
+	}
+}
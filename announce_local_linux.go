@@ -0,0 +1,254 @@
+//go:build linux
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// capNetRaw is CAP_NET_RAW, the only capability this hook needs: opening an
+// AF_PACKET socket to forge the gratuitous ARP / unsolicited NA frame.
+const capNetRaw = 13
+
+// LocalAnnounceHook broadcasts the announcement from a raw socket on this
+// host, which is expected to be running directly on the failover target.
+type LocalAnnounceHook struct {
+	iface string
+}
+
+func NewLocalAnnounceHook(iface string) *LocalAnnounceHook {
+	return &LocalAnnounceHook{iface: iface}
+}
+
+func (h *LocalAnnounceHook) Announce(ctx context.Context, target Target, eip string, eipv6 string) error {
+	if h.iface == "" {
+		return fmt.Errorf("local announce hook requires an interface name")
+	}
+
+	iface, err := net.InterfaceByName(h.iface)
+	if err != nil {
+		return fmt.Errorf("lookup interface %q: %w", h.iface, err)
+	}
+
+	if err := raiseAmbientCapNetRaw(); err != nil {
+		// Most deployments run ha-flow as an unprivileged user and rely on
+		// `setcap cap_net_raw=p` on the binary, in which case the process
+		// already holds the capability and raising it ambiently is
+		// unnecessary; only treat this as fatal once the actual send fails.
+		slog.Warn(fmt.Sprintf("could not raise ambient CAP_NET_RAW, continuing with inherited privileges: %v", err))
+	}
+
+	if err := sendGratuitousARP(iface, eip); err != nil {
+		return fmt.Errorf("send gratuitous arp: %w", err)
+	}
+
+	if eipv6 != "" {
+		if err := sendUnsolicitedNA(iface, eipv6); err != nil {
+			return fmt.Errorf("send unsolicited neighbor advertisement: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// raiseAmbientCapNetRaw marks CAP_NET_RAW as inheritable and ambient for
+// this process so it survives should ha-flow later exec a privilege-dropped
+// helper, mirroring how the skywire VPN client acquires the single
+// capability it needs instead of running fully as root. PR_SET_KEEPCAPS is
+// set first so the capability isn't dropped on a subsequent setuid.
+func raiseAmbientCapNetRaw() error {
+	if err := unix.Prctl(unix.PR_SET_KEEPCAPS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("PR_SET_KEEPCAPS: %w", err)
+	}
+	hdr := unix.CapUserHeader{Version: unix.LINUX_CAPABILITY_VERSION_3}
+	var data [2]unix.CapUserData
+	if err := unix.Capget(&hdr, &data[0]); err != nil {
+		return fmt.Errorf("capget: %w", err)
+	}
+	data[0].Inheritable |= 1 << capNetRaw
+	if err := unix.Capset(&hdr, &data[0]); err != nil {
+		return fmt.Errorf("capset inheritable CAP_NET_RAW: %w", err)
+	}
+	if err := unix.Prctl(unix.PR_CAP_AMBIENT, unix.PR_CAP_AMBIENT_RAISE, capNetRaw, 0, 0); err != nil {
+		return fmt.Errorf("PR_CAP_AMBIENT_RAISE(CAP_NET_RAW): %w", err)
+	}
+	return nil
+}
+
+// sendGratuitousARP opens an AF_PACKET socket and broadcasts an ARP reply
+// claiming eip for iface's hardware address, so neighbours refresh their
+// ARP cache instead of keeping the old mapping until it times out.
+func sendGratuitousARP(iface *net.Interface, eip string) error {
+	ip := net.ParseIP(eip).To4()
+	if ip == nil {
+		return fmt.Errorf("not an ipv4 address: %v", eip)
+	}
+
+	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_RAW, htons(unix.ETH_P_ARP))
+	if err != nil {
+		return fmt.Errorf("open AF_PACKET socket: %w", err)
+	}
+	defer unix.Close(fd)
+
+	frame := buildGratuitousARPFrame(iface.HardwareAddr, ip)
+
+	addr := unix.SockaddrLinklayer{
+		Protocol: htons(unix.ETH_P_ARP),
+		Ifindex:  iface.Index,
+		Halen:    6,
+	}
+	copy(addr.Addr[:], broadcastMAC())
+
+	if err := unix.Sendto(fd, frame, 0, &addr); err != nil {
+		return fmt.Errorf("sendto: %w", err)
+	}
+
+	return nil
+}
+
+// buildGratuitousARPFrame builds a full ethernet frame carrying a
+// gratuitous ARP reply: broadcast destination, sender and target protocol
+// address both set to eip, per RFC 5227 semantics.
+func buildGratuitousARPFrame(srcMAC net.HardwareAddr, eip net.IP) []byte {
+	frame := make([]byte, 14+28)
+
+	copy(frame[0:6], broadcastMAC())
+	copy(frame[6:12], srcMAC)
+	putUint16(frame[12:14], unix.ETH_P_ARP)
+
+	arp := frame[14:]
+	putUint16(arp[0:2], 1)      // hardware type: ethernet
+	putUint16(arp[2:4], 0x0800) // protocol type: ipv4
+	arp[4] = 6                  // hardware address length
+	arp[5] = 4                  // protocol address length
+	putUint16(arp[6:8], 2)      // opcode: reply
+	copy(arp[8:14], srcMAC)     // sender hardware address
+	copy(arp[14:18], eip)       // sender protocol address (the EIP itself)
+	copy(arp[18:24], srcMAC)    // target hardware address
+	copy(arp[24:28], eip)       // target protocol address (gratuitous: same as sender)
+
+	return frame
+}
+
+// sendUnsolicitedNA opens an AF_PACKET socket and broadcasts an unsolicited
+// IPv6 Neighbor Advertisement claiming eipv6 for iface's hardware address,
+// the IPv6 analogue of a gratuitous ARP reply.
+func sendUnsolicitedNA(iface *net.Interface, eipv6 string) error {
+	ip := net.ParseIP(eipv6).To16()
+	if ip == nil || ip.To4() != nil {
+		return fmt.Errorf("not an ipv6 address: %v", eipv6)
+	}
+
+	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_RAW, htons(unix.ETH_P_IPV6))
+	if err != nil {
+		return fmt.Errorf("open AF_PACKET socket: %w", err)
+	}
+	defer unix.Close(fd)
+
+	frame := buildUnsolicitedNAFrame(iface.HardwareAddr, ip)
+
+	addr := unix.SockaddrLinklayer{
+		Protocol: htons(unix.ETH_P_IPV6),
+		Ifindex:  iface.Index,
+		Halen:    6,
+	}
+	copy(addr.Addr[:], multicastNDPMAC())
+
+	if err := unix.Sendto(fd, frame, 0, &addr); err != nil {
+		return fmt.Errorf("sendto: %w", err)
+	}
+
+	return nil
+}
+
+// buildUnsolicitedNAFrame builds an ethernet frame carrying an ICMPv6
+// Neighbor Advertisement with the override flag set, so receivers replace
+// their existing neighbor cache entry for eipv6 rather than ignoring an
+// advertisement they did not solicit.
+func buildUnsolicitedNAFrame(srcMAC net.HardwareAddr, eipv6 net.IP) []byte {
+	const icmp6Len = 4 + 4 + 16 + 8 // type/code/checksum, reserved+flags, target address, options
+	frame := make([]byte, 14+40+icmp6Len)
+
+	copy(frame[0:6], multicastNDPMAC())
+	copy(frame[6:12], srcMAC)
+	putUint16(frame[12:14], unix.ETH_P_IPV6)
+
+	ip6 := frame[14:54]
+	ip6[0] = 0x60 // version 6
+	putUint16(ip6[4:6], uint16(icmp6Len))
+	ip6[6] = 58 // next header: ICMPv6
+	ip6[7] = 255
+	copy(ip6[8:24], eipv6)                      // source: the EIP itself
+	copy(ip6[24:40], net.IPv6linklocalallnodes) // destination: all-nodes multicast
+
+	icmp6 := frame[54:]
+	icmp6[0] = 136  // type: neighbor advertisement
+	icmp6[1] = 0    // code
+	icmp6[4] = 0x20 // flags: router=0, solicited=0, override=1
+	copy(icmp6[8:24], eipv6)
+	icmp6[24] = 2 // option type: target link-layer address
+	icmp6[25] = 1 // option length, in units of 8 bytes
+	copy(icmp6[26:32], srcMAC)
+
+	// a raw AF_PACKET send bypasses the kernel's usual checksum offload, so
+	// the mandatory ICMPv6 checksum (RFC 4443) has to be computed here or
+	// every stack that validates it (including Linux) drops the packet.
+	putUint16(icmp6[2:4], icmp6Checksum(ip6[8:24], ip6[24:40], icmp6))
+
+	return frame
+}
+
+// icmp6Checksum computes the ICMPv6 checksum over the IPv6 pseudo-header
+// (source address, destination address, upper-layer length, next header)
+// followed by payload, per RFC 2460 section 8.1 and RFC 4443 section 2.3.
+// payload's checksum field must be zeroed by the caller before calling this.
+func icmp6Checksum(src, dst net.IP, payload []byte) uint16 {
+	var sum uint32
+
+	addWords := func(b []byte) {
+		for i := 0; i+1 < len(b); i += 2 {
+			sum += uint32(b[i])<<8 | uint32(b[i+1])
+		}
+		if len(b)%2 == 1 {
+			sum += uint32(b[len(b)-1]) << 8
+		}
+	}
+
+	addWords(src)
+	addWords(dst)
+
+	var lengthAndNextHeader [8]byte
+	putUint16(lengthAndNextHeader[2:4], uint16(len(payload)))
+	lengthAndNextHeader[7] = 58 // next header: ICMPv6
+	addWords(lengthAndNextHeader[:])
+
+	addWords(payload)
+
+	for sum>>16 != 0 {
+		sum = (sum & 0xffff) + (sum >> 16)
+	}
+
+	return ^uint16(sum)
+}
+
+func multicastNDPMAC() net.HardwareAddr {
+	return net.HardwareAddr{0x33, 0x33, 0x00, 0x00, 0x00, 0x01}
+}
+
+func broadcastMAC() net.HardwareAddr {
+	return net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+}
+
+func putUint16(b []byte, v uint16) {
+	b[0] = byte(v >> 8)
+	b[1] = byte(v)
+}
+
+func htons(v uint16) int {
+	return int(v<<8) | int(v>>8)
+}
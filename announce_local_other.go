@@ -0,0 +1,20 @@
+//go:build !linux
+
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// LocalAnnounceHook is only implemented on Linux, where AF_PACKET raw
+// sockets are available to forge the ARP/NDP frame.
+type LocalAnnounceHook struct{}
+
+func NewLocalAnnounceHook(iface string) *LocalAnnounceHook {
+	return &LocalAnnounceHook{}
+}
+
+func (h *LocalAnnounceHook) Announce(ctx context.Context, target Target, eip string, eipv6 string) error {
+	return fmt.Errorf("local announce mode is only supported on linux")
+}
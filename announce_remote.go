@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// RemoteAnnounceConfig configures the SSH-based announce hook, for
+// operators who run ha-flow off the instances it manages.
+type RemoteAnnounceConfig struct {
+	User           string
+	Port           int
+	PrivateKeyPath string
+	Timeout        time.Duration
+
+	// KnownHostsPath is an OpenSSH known_hosts file used to verify the
+	// target's host key. ha-flow runs arping/ndsend as root on whatever
+	// instance this connects to, so host key checking is mandatory rather
+	// than opt-in.
+	KnownHostsPath string
+
+	// Iface is the network interface name to announce on, as seen by the
+	// target instance's own OS.
+	Iface string
+}
+
+// RemoteAnnounceHook SSHes into the failover target and runs arping/ndsend
+// there, for deployments where ha-flow itself does not run on the target.
+type RemoteAnnounceHook struct {
+	cfg             RemoteAnnounceConfig
+	signer          ssh.Signer
+	hostKeyCallback ssh.HostKeyCallback
+}
+
+func NewRemoteAnnounceHook(cfg RemoteAnnounceConfig) (*RemoteAnnounceHook, error) {
+	if cfg.Iface == "" {
+		return nil, fmt.Errorf("remote announce hook requires an interface name")
+	}
+	if cfg.KnownHostsPath == "" {
+		return nil, fmt.Errorf("remote announce hook requires a known_hosts file to verify target host keys against")
+	}
+
+	key, err := os.ReadFile(cfg.PrivateKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("read private key: %w", err)
+	}
+
+	signer, err := ssh.ParsePrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("parse private key: %w", err)
+	}
+
+	hostKeyCallback, err := knownhosts.New(cfg.KnownHostsPath)
+	if err != nil {
+		return nil, fmt.Errorf("read known_hosts %v: %w", cfg.KnownHostsPath, err)
+	}
+
+	if cfg.Port == 0 {
+		cfg.Port = 22
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+
+	return &RemoteAnnounceHook{cfg: cfg, signer: signer, hostKeyCallback: hostKeyCallback}, nil
+}
+
+func (h *RemoteAnnounceHook) Announce(ctx context.Context, target Target, eip string, eipv6 string) error {
+	addr, err := h.dialAddr(target)
+	if err != nil {
+		return err
+	}
+
+	client, err := h.dial(ctx, addr)
+	if err != nil {
+		return fmt.Errorf("dial %v: %w", addr, err)
+	}
+	defer client.Close()
+
+	if err := h.run(client, fmt.Sprintf("arping -U -c 1 -I %s %s", h.cfg.Iface, eip)); err != nil {
+		return fmt.Errorf("arping: %w", err)
+	}
+
+	if eipv6 != "" {
+		if err := h.run(client, fmt.Sprintf("ndsend %s %s", eipv6, h.cfg.Iface)); err != nil {
+			return fmt.Errorf("ndsend: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// dialAddr resolves the address to SSH into, using the target's private IP
+// since the instance's display name is not generally a resolvable hostname.
+func (h *RemoteAnnounceHook) dialAddr(target Target) (string, error) {
+	if target.PrivateIP == "" {
+		return "", fmt.Errorf("target has no resolvable address")
+	}
+	return net.JoinHostPort(target.PrivateIP, fmt.Sprintf("%d", h.cfg.Port)), nil
+}
+
+func (h *RemoteAnnounceHook) dial(ctx context.Context, addr string) (*ssh.Client, error) {
+	config := &ssh.ClientConfig{
+		User:            h.cfg.User,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(h.signer)},
+		HostKeyCallback: h.hostKeyCallback,
+		Timeout:         h.cfg.Timeout,
+	}
+
+	d := net.Dialer{Timeout: h.cfg.Timeout}
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return ssh.NewClient(sshConn, chans, reqs), nil
+}
+
+func (h *RemoteAnnounceHook) run(client *ssh.Client, cmd string) error {
+	session, err := client.NewSession()
+	if err != nil {
+		return fmt.Errorf("open session: %w", err)
+	}
+	defer session.Close()
+
+	var stderr bytes.Buffer
+	session.Stderr = &stderr
+
+	if err := session.Run(cmd); err != nil {
+		return fmt.Errorf("%w: %s", err, stderr.String())
+	}
+
+	return nil
+}
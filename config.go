@@ -0,0 +1,378 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/flowswiss/goclient"
+	"github.com/flowswiss/goclient/compute"
+	"gopkg.in/yaml.v3"
+)
+
+// Duration wraps time.Duration so config files can use human-readable
+// strings like "5s" instead of raw nanosecond integers.
+type Duration time.Duration
+
+func (d Duration) Duration() time.Duration { return time.Duration(d) }
+
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	var s string
+	if err := node.Decode(&s); err != nil {
+		return err
+	}
+
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q: %w", s, err)
+	}
+
+	*d = Duration(parsed)
+	return nil
+}
+
+// HealthConfig is the YAML representation of HealthCheckConfig. Probe
+// cadence is not configured here; it comes from the group's top-level
+// poll_interval (GroupConfig.PollInterval).
+type HealthConfig struct {
+	Type ProbeType `yaml:"type"`
+
+	Timeout Duration `yaml:"timeout"`
+
+	FailureThreshold int `yaml:"fail_threshold"`
+	SuccessThreshold int `yaml:"success_threshold"`
+
+	TCP struct {
+		Port int `yaml:"port"`
+	} `yaml:"tcp,omitempty"`
+
+	HTTP struct {
+		Port           int    `yaml:"port"`
+		Path           string `yaml:"path"`
+		ExpectedStatus int    `yaml:"expect_status"`
+	} `yaml:"http,omitempty"`
+}
+
+// validate rejects a YAML health block that NewDaemon could not actually
+// probe with, so a typo'd probe type or a zero timeout/threshold is caught
+// at config-load time instead of crashing the daemon or silently
+// manufacturing guaranteed spurious failovers.
+func (h HealthConfig) validate() error {
+	if err := h.toHealthCheckConfig().validate(); err != nil {
+		return fmt.Errorf("health: %w", err)
+	}
+	return nil
+}
+
+func (h HealthConfig) toHealthCheckConfig() HealthCheckConfig {
+	return HealthCheckConfig{
+		Type:             h.Type,
+		Timeout:          h.Timeout.Duration(),
+		FailureThreshold: h.FailureThreshold,
+		SuccessThreshold: h.SuccessThreshold,
+		TCP:              TCPProbe{Port: h.TCP.Port},
+		HTTP:             HTTPProbe{Port: h.HTTP.Port, Path: h.HTTP.Path, ExpectedStatus: h.HTTP.ExpectedStatus},
+	}
+}
+
+// CoordinatorConfig is the YAML representation of a group's leader election
+// backend, mirroring the --coordinator/--etcd-* flags.
+type CoordinatorConfig struct {
+	Backend string `yaml:"backend"`
+	NodeID  string `yaml:"node_id"`
+	Etcd    struct {
+		Endpoints []string `yaml:"endpoints"`
+		LeaseTTL  Duration `yaml:"lease_ttl"`
+	} `yaml:"etcd,omitempty"`
+}
+
+func (c *CoordinatorConfig) toCoordinator(eip string) (Coordinator, error) {
+	if c == nil {
+		// the group did not override coordination, so fall back to the
+		// CLI flags, keeping the flat flag interface working as shorthand.
+		return coordinatorFromFlags(eip)
+	}
+
+	if c.Backend == "" || c.Backend == "none" {
+		return NewNoopCoordinator(c.NodeID), nil
+	}
+
+	if c.Backend != "etcd" {
+		return nil, fmt.Errorf("unknown coordinator backend: %v", c.Backend)
+	}
+
+	if len(c.Etcd.Endpoints) == 0 {
+		return nil, fmt.Errorf("etcd coordinator requires at least one endpoint")
+	}
+
+	leaseTTL := c.Etcd.LeaseTTL.Duration()
+	if leaseTTL <= 0 {
+		leaseTTL = 10 * time.Second
+	}
+
+	return NewEtcdCoordinator(c.NodeID, eip, c.Etcd.Endpoints, leaseTTL)
+}
+
+// SelectorConfigYAML is the YAML representation of SelectorConfig.
+type SelectorConfigYAML struct {
+	Priorities map[string]int `yaml:"priorities,omitempty"`
+
+	PreferUnattached float64 `yaml:"prefer_unattached,omitempty"`
+	PreferSameZone   float64 `yaml:"prefer_same_zone,omitempty"`
+	PreferHealthy    float64 `yaml:"prefer_healthy,omitempty"`
+	PreferLowLoad    float64 `yaml:"prefer_low_load,omitempty"`
+
+	LoadMetricsURL string `yaml:"load_metrics_url,omitempty"`
+}
+
+func (s *SelectorConfigYAML) toSelectorConfig() SelectorConfig {
+	if s == nil {
+		return selectorConfigFromFlags()
+	}
+
+	return SelectorConfig{
+		Priorities:             s.Priorities,
+		PreferUnattached:       s.PreferUnattached,
+		PreferSameZone:         s.PreferSameZone,
+		PreferHealthy:          s.PreferHealthy,
+		PreferLowLoad:          s.PreferLowLoad,
+		LoadMetricsURLTemplate: s.LoadMetricsURL,
+	}
+}
+
+// GroupConfig declares one independently managed HA Elastic IP.
+type GroupConfig struct {
+	Name    string   `yaml:"name"`
+	EIP     string   `yaml:"eip"`
+	EIPv6   string   `yaml:"eipv6,omitempty"`
+	PeerIPs []string `yaml:"peers"`
+
+	Health        *HealthConfig           `yaml:"health,omitempty"`
+	Coordinator   *CoordinatorConfig      `yaml:"coordinator,omitempty"`
+	Announce      *AnnounceConfigYAML     `yaml:"announce,omitempty"`
+	Selector      *SelectorConfigYAML     `yaml:"selector,omitempty"`
+	Notifications *NotificationConfigYAML `yaml:"notifications,omitempty"`
+
+	StatusAddr   string   `yaml:"status_addr,omitempty"`
+	PollInterval Duration `yaml:"poll_interval,omitempty"`
+}
+
+func (g GroupConfig) validate() error {
+	if g.Name == "" {
+		return fmt.Errorf("group is missing a name")
+	}
+	if g.EIP == "" {
+		return fmt.Errorf("group %q is missing eip", g.Name)
+	}
+	if len(g.PeerIPs) == 0 {
+		return fmt.Errorf("group %q has no peers", g.Name)
+	}
+	if g.Health != nil {
+		if err := g.Health.validate(); err != nil {
+			return fmt.Errorf("group %q: %w", g.Name, err)
+		}
+	}
+	return nil
+}
+
+// Config is the top-level shape of the ha-flow YAML config file, declaring
+// an arbitrary number of independently managed HA groups.
+type Config struct {
+	Groups []GroupConfig `yaml:"groups"`
+}
+
+func loadConfig(path string) (Config, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("read config: %w", err)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(raw, &cfg); err != nil {
+		return Config{}, fmt.Errorf("parse config: %w", err)
+	}
+
+	if len(cfg.Groups) == 0 {
+		return Config{}, fmt.Errorf("config declares no groups")
+	}
+
+	names := map[string]bool{}
+	for _, group := range cfg.Groups {
+		if err := group.validate(); err != nil {
+			return Config{}, err
+		}
+		if names[group.Name] {
+			return Config{}, fmt.Errorf("duplicate group name: %v", group.Name)
+		}
+		names[group.Name] = true
+	}
+
+	return cfg, nil
+}
+
+// configFromFlags builds a single-group Config from the legacy
+// --eip/--peers flags, so the flat flag interface keeps working as
+// shorthand for a one-group config.
+func configFromFlags(haEIP string, haPeerIPs []string) Config {
+	return Config{
+		Groups: []GroupConfig{
+			{
+				Name:    haEIP,
+				EIP:     haEIP,
+				PeerIPs: haPeerIPs,
+			},
+		},
+	}
+}
+
+// GroupWorker drives a single HA group, either as a one-shot failover or,
+// when wrapped in a Daemon, as a long-running watcher.
+type GroupWorker struct {
+	client *goclient.Client
+	group  GroupConfig
+}
+
+func NewGroupWorker(client *goclient.Client, group GroupConfig) *GroupWorker {
+	return &GroupWorker{client: client, group: group}
+}
+
+// RunOnce performs a single failover for this group, reusing the same
+// detach/pick-target/attach flow as the original single-group CLI.
+func (w *GroupWorker) RunOnce(ctx context.Context) error {
+	announce, err := newAnnounceHook(w.announceConfig())
+	if err != nil {
+		return fmt.Errorf("group %q: %w", w.group.Name, err)
+	}
+
+	peerHealth := NewPeerHealthPool(w.healthConfig())
+	return performFailover(
+		ctx, w.client, w.group.Name, w.group.EIP, w.group.PeerIPs, w.group.Selector.toSelectorConfig(), peerHealth,
+		announce, w.group.EIPv6, FailoverReasonManual, w.notifier(),
+	)
+}
+
+// notifier returns the group's own notification settings, falling back to
+// the CLI flags so the flat flag interface keeps working as shorthand.
+func (w *GroupWorker) notifier() Notifier {
+	return w.group.Notifications.toNotifier()
+}
+
+// announceConfig returns the group's own announce settings, falling back to
+// the CLI flags so the flat flag interface keeps working as shorthand.
+func (w *GroupWorker) announceConfig() AnnounceConfig {
+	if w.group.Announce != nil {
+		return w.group.Announce.toAnnounceConfig()
+	}
+	return announceConfigFromFlags()
+}
+
+// healthConfig returns the group's own health check settings, falling back
+// to the CLI flags so the flat flag interface keeps working as shorthand.
+func (w *GroupWorker) healthConfig() HealthCheckConfig {
+	if w.group.Health != nil {
+		return w.group.Health.toHealthCheckConfig()
+	}
+	return HealthCheckConfig{
+		Type:             ProbeType(flagProbeType),
+		Timeout:          flagProbeTimeout,
+		FailureThreshold: flagProbeFailThreshold,
+		SuccessThreshold: flagProbeSuccessThreshold,
+		TCP:              TCPProbe{Port: flagProbePort},
+		HTTP:             HTTPProbe{Port: flagProbePort, Path: flagProbePath, ExpectedStatus: flagProbeExpectStatus},
+	}
+}
+
+// Daemon builds a long-running watcher for this group, using the group's
+// health check and coordinator settings, falling back to the CLI flags for
+// anything the group does not override.
+func (w *GroupWorker) Daemon() (*Daemon, error) {
+	health := w.healthConfig()
+	if err := health.validate(); err != nil {
+		return nil, fmt.Errorf("group %q: %w", w.group.Name, err)
+	}
+
+	coordinator, err := w.group.Coordinator.toCoordinator(w.group.EIP)
+	if err != nil {
+		return nil, fmt.Errorf("group %q: %w", w.group.Name, err)
+	}
+
+	announce, err := newAnnounceHook(w.announceConfig())
+	if err != nil {
+		return nil, fmt.Errorf("group %q: %w", w.group.Name, err)
+	}
+
+	pollInterval := flagProbeInterval
+	if w.group.PollInterval.Duration() > 0 {
+		pollInterval = w.group.PollInterval.Duration()
+	}
+
+	statusAddr := flagStatusAddr
+	if w.group.StatusAddr != "" {
+		statusAddr = w.group.StatusAddr
+	}
+
+	return NewDaemon(w.client, DaemonConfig{
+		Group:        w.group.Name,
+		EIP:          w.group.EIP,
+		EIPv6:        w.group.EIPv6,
+		PeerIPs:      w.group.PeerIPs,
+		Health:       health,
+		Selector:     w.group.Selector.toSelectorConfig(),
+		Coordinator:  coordinator,
+		Announce:     announce,
+		Notifier:     w.notifier(),
+		PollInterval: pollInterval,
+		StatusAddr:   statusAddr,
+	}), nil
+}
+
+// checkConfig validates every group against the Flow API without performing
+// any mutation: the EIP must exist, every peer private IP must belong to a
+// real network interface on the account, and — if the EIP is currently
+// attached — that interface's instance must be in the same availability zone
+// as the attachment, the same membership signal SameZonePolicy uses to pick
+// failover targets. Zone is the closest thing to network membership goclient
+// exposes to ha-flow here, so this still lets a peer on the right zone but an
+// unrelated subnet through; there is no subnet/VPC ID on compute.NetworkInterface
+// to check against.
+func checkConfig(ctx context.Context, client *goclient.Client, cfg Config) error {
+	eipService := compute.NewElasticIPService(client)
+	serverService := compute.NewServerService(client)
+
+	instances, err := serverService.List(ctx, goclient.Cursor{NoFilter: 1})
+	if err != nil {
+		return fmt.Errorf("list instances: %w", err)
+	}
+
+	zoneByPrivateIP := map[string]int{}
+	for _, instance := range instances.Items {
+		networkInterfaces, err := serverService.NetworkInterfaces(instance.ID).List(ctx, goclient.Cursor{NoFilter: 1})
+		if err != nil {
+			return fmt.Errorf("list network interfaces for instance %v: %w", instance.ID, err)
+		}
+		for _, ni := range networkInterfaces.Items {
+			zoneByPrivateIP[ni.PrivateIP] = instance.Zone.ID
+		}
+	}
+
+	for _, group := range cfg.Groups {
+		elasticIP, err := findElasticIPID(ctx, eipService, group.EIP)
+		if err != nil {
+			return fmt.Errorf("group %q: %w", group.Name, err)
+		}
+		eipZoneID, eipAttached := zoneByPrivateIP[elasticIP.PrivateIP]
+
+		for _, peer := range group.PeerIPs {
+			zoneID, known := zoneByPrivateIP[peer]
+			if !known {
+				return fmt.Errorf("group %q: peer %v is not a known instance private ip", group.Name, peer)
+			}
+			if eipAttached && zoneID != eipZoneID {
+				return fmt.Errorf("group %q: peer %v is in a different availability zone than elastic ip %v's current attachment", group.Name, peer, group.EIP)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	flagNodeID        = ""
+	flagCoordinator   = "none"
+	flagEtcdEndpoints = ""
+	flagEtcdLeaseTTL  = time.Duration(0)
+)
+
+func registerCoordinatorFlags() {
+	hostname, _ := os.Hostname()
+	flag.StringVar(&flagNodeID, "node-id", hostname, "identity of this ha-flow instance, used for coordination")
+	flag.StringVar(&flagCoordinator, "coordinator", "none", "leader-election backend to use (none, etcd)")
+	flag.StringVar(&flagEtcdEndpoints, "etcd-endpoints", "", "comma-separated etcd endpoints (required if --coordinator=etcd)")
+	flag.DurationVar(&flagEtcdLeaseTTL, "etcd-lease-ttl", 10*time.Second, "etcd lease TTL for the coordinator session")
+}
+
+// coordinatorFromFlags builds the Coordinator implementation selected by
+// --coordinator for the given HA Elastic IP.
+func coordinatorFromFlags(eip string) (Coordinator, error) {
+	switch flagCoordinator {
+	case "", "none":
+		return NewNoopCoordinator(flagNodeID), nil
+	case "etcd":
+		if flagEtcdEndpoints == "" {
+			return nil, fmt.Errorf("--etcd-endpoints is required when --coordinator=etcd")
+		}
+		endpoints := strings.Split(flagEtcdEndpoints, ",")
+		return NewEtcdCoordinator(flagNodeID, eip, endpoints, flagEtcdLeaseTTL)
+	default:
+		return nil, fmt.Errorf("unknown coordinator backend: %v", flagCoordinator)
+	}
+}
+
+// Coordinator arbitrates which of several ha-flow instances watching the
+// same Elastic IP is allowed to act on it. Exactly one instance should ever
+// be the leader at a time; the rest observe and stand by to take over if the
+// leader disappears.
+type Coordinator interface {
+	// Campaign blocks until this instance becomes leader or ctx is
+	// cancelled, whichever happens first.
+	Campaign(ctx context.Context) error
+	// Resign gives up leadership voluntarily, allowing another instance to
+	// take over immediately instead of waiting for a lease to expire.
+	Resign(ctx context.Context) error
+	// IsLeader reports whether this instance currently holds leadership.
+	IsLeader() bool
+	// Observe streams the node ID of the current leader, including changes
+	// as leadership moves between instances.
+	Observe(ctx context.Context) <-chan string
+}
+
+// NoopCoordinator is the single-node Coordinator: it is always leader,
+// preserving ha-flow's original behaviour of acting unconditionally.
+type NoopCoordinator struct {
+	nodeID string
+}
+
+func NewNoopCoordinator(nodeID string) *NoopCoordinator {
+	return &NoopCoordinator{nodeID: nodeID}
+}
+
+func (n *NoopCoordinator) Campaign(ctx context.Context) error { return nil }
+func (n *NoopCoordinator) Resign(ctx context.Context) error   { return nil }
+func (n *NoopCoordinator) IsLeader() bool                     { return true }
+
+func (n *NoopCoordinator) Observe(ctx context.Context) <-chan string {
+	ch := make(chan string, 1)
+	ch <- n.nodeID
+	close(ch)
+	return ch
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+// EtcdCoordinator elects a single leader per HA Elastic IP using etcd's
+// lease-backed compare-and-swap primitives. Every instance registers itself
+// under /ha-flow/<eip>/members/<node-id> for the lifetime of its lease and
+// campaigns for /ha-flow/<eip>/leader; only the campaign winner is allowed
+// to drive a failover, and followers are freed to take over as soon as the
+// leader's lease expires or it resigns.
+type EtcdCoordinator struct {
+	nodeID string
+	eip    string
+	client *clientv3.Client
+
+	leaseTTL time.Duration
+
+	session  *concurrency.Session
+	election *concurrency.Election
+
+	isLeader atomic.Bool
+}
+
+func NewEtcdCoordinator(nodeID, eip string, endpoints []string, leaseTTL time.Duration) (*EtcdCoordinator, error) {
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   endpoints,
+		DialTimeout: 5 * time.Second,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("connect to etcd: %w", err)
+	}
+
+	return &EtcdCoordinator{
+		nodeID:   nodeID,
+		eip:      eip,
+		client:   client,
+		leaseTTL: leaseTTL,
+	}, nil
+}
+
+func (e *EtcdCoordinator) memberKey() string {
+	return fmt.Sprintf("/ha-flow/%s/members/%s", e.eip, e.nodeID)
+}
+
+func (e *EtcdCoordinator) leaderKey() string {
+	return fmt.Sprintf("/ha-flow/%s/leader", e.eip)
+}
+
+// Campaign registers this node as a member with a leased key and then
+// blocks on the leader election until it wins or ctx is cancelled.
+func (e *EtcdCoordinator) Campaign(ctx context.Context) error {
+	session, err := concurrency.NewSession(e.client, concurrency.WithTTL(int(e.leaseTTL.Seconds())))
+	if err != nil {
+		return fmt.Errorf("create etcd session: %w", err)
+	}
+	e.session = session
+
+	if _, err := e.client.Put(ctx, e.memberKey(), e.nodeID, clientv3.WithLease(session.Lease())); err != nil {
+		return fmt.Errorf("register member key: %w", err)
+	}
+
+	e.election = concurrency.NewElection(session, e.leaderKey())
+	if err := e.election.Campaign(ctx, e.nodeID); err != nil {
+		return fmt.Errorf("campaign for leadership: %w", err)
+	}
+
+	e.isLeader.Store(true)
+	return nil
+}
+
+// Resign voluntarily gives up leadership so another member can take over
+// without waiting for the lease to expire.
+func (e *EtcdCoordinator) Resign(ctx context.Context) error {
+	if e.election == nil || !e.isLeader.Load() {
+		return nil
+	}
+
+	if err := e.election.Resign(ctx); err != nil {
+		return fmt.Errorf("resign leadership: %w", err)
+	}
+	e.isLeader.Store(false)
+
+	if e.session != nil {
+		return e.session.Close()
+	}
+	return nil
+}
+
+func (e *EtcdCoordinator) IsLeader() bool {
+	return e.isLeader.Load()
+}
+
+// Observe streams the node ID of the current leader as leadership changes
+// hands, until ctx is cancelled. Unlike Campaign it does not require this
+// node to hold a session, so followers can watch the leader key without
+// participating in the election themselves.
+func (e *EtcdCoordinator) Observe(ctx context.Context) <-chan string {
+	out := make(chan string)
+
+	go func() {
+		defer close(out)
+
+		if resp, err := e.client.Get(ctx, e.leaderKey(), clientv3.WithSort(clientv3.SortByCreateRevision, clientv3.SortAscend), clientv3.WithPrefix()); err == nil && len(resp.Kvs) > 0 {
+			select {
+			case out <- string(resp.Kvs[0].Value):
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		watch := e.client.Watch(ctx, e.leaderKey(), clientv3.WithPrefix())
+		for range watch {
+			// any put or delete under the prefix may change who holds the
+			// oldest campaign key, so re-read it rather than reporting the
+			// key that changed, which may be a follower joining the election.
+			resp, err := e.client.Get(ctx, e.leaderKey(), clientv3.WithSort(clientv3.SortByCreateRevision, clientv3.SortAscend), clientv3.WithPrefix())
+			if err != nil || len(resp.Kvs) == 0 {
+				continue
+			}
+			select {
+			case out <- string(resp.Kvs[0].Value):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
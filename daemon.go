@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/flowswiss/goclient"
+	"github.com/flowswiss/goclient/compute"
+)
+
+// State is the role ha-flow believes the watched Elastic IP is currently in.
+type State string
+
+const (
+	StateUnknown State = "unknown"
+	StatePrimary State = "primary"
+	StateStandby State = "standby"
+)
+
+var (
+	flagProbeType             = ""
+	flagProbePort             = 0
+	flagProbePath             = ""
+	flagProbeExpectStatus     = 0
+	flagProbeInterval         = time.Duration(0)
+	flagProbeTimeout          = time.Duration(0)
+	flagProbeFailThreshold    = 0
+	flagProbeSuccessThreshold = 0
+	flagStatusAddr            = ""
+)
+
+func registerDaemonFlags() {
+	flag.StringVar(&flagProbeType, "probe-type", "tcp", "health probe type for --watch (tcp, http, icmp)")
+	flag.IntVar(&flagProbePort, "probe-port", 80, "port used by tcp/http probes")
+	flag.StringVar(&flagProbePath, "probe-path", "/", "path used by the http probe")
+	flag.IntVar(&flagProbeExpectStatus, "probe-expect-status", http.StatusOK, "status code expected by the http probe")
+	flag.DurationVar(&flagProbeInterval, "probe-interval", 5*time.Second, "time between health probes")
+	flag.DurationVar(&flagProbeTimeout, "probe-timeout", 2*time.Second, "per-probe timeout")
+	flag.IntVar(&flagProbeFailThreshold, "probe-fail-threshold", 3, "consecutive failed probes before a failover is triggered")
+	flag.IntVar(&flagProbeSuccessThreshold, "probe-success-threshold", 2, "consecutive successful probes before the target is considered healthy again")
+	flag.StringVar(&flagStatusAddr, "status-addr", "", "address to serve daemon status on, e.g. :8080 (disabled if empty)")
+}
+
+// DaemonConfig bundles everything the daemon needs to watch and, if
+// necessary, fail over a single HA Elastic IP.
+type DaemonConfig struct {
+	Group   string
+	EIP     string
+	EIPv6   string
+	PeerIPs []string
+
+	Health      HealthCheckConfig
+	Selector    SelectorConfig
+	Coordinator Coordinator
+	Announce    AnnounceHook
+	Notifier    Notifier
+
+	PollInterval time.Duration
+	StatusAddr   string
+}
+
+// Daemon keeps a single HA Elastic IP under supervision: it periodically
+// probes whichever instance currently holds the EIP and only triggers the
+// existing detach/pick-target/attach flow once the probe's hysteresis
+// reports it down.
+type Daemon struct {
+	client *goclient.Client
+	cfg    DaemonConfig
+
+	checker    *HealthChecker
+	peerHealth *PeerHealthPool
+
+	mu            sync.Mutex
+	state         State
+	lastProbeOK   bool
+	lastProbeErr  error
+	lastProbeAt   time.Time
+	lastFailover  time.Time
+	currentLeader string
+}
+
+func NewDaemon(client *goclient.Client, cfg DaemonConfig) *Daemon {
+	checker, err := NewHealthChecker(cfg.Health)
+	if err != nil {
+		// health is validated by the caller (GroupWorker.Daemon), so this can
+		// only happen if a caller constructs DaemonConfig by hand incorrectly.
+		panic(err)
+	}
+
+	return &Daemon{
+		client:     client,
+		cfg:        cfg,
+		checker:    checker,
+		peerHealth: NewPeerHealthPool(cfg.Health),
+		state:      StateUnknown,
+	}
+}
+
+// Run blocks, polling the current EIP holder on cfg.PollInterval, until ctx
+// is cancelled. If a Coordinator is configured, campaigning for leadership
+// happens in the background: the status endpoint and probe ticker start
+// immediately so a follower stays observable (via Observe and its own
+// ticks) instead of sitting inert until it wins the election. Leadership is
+// resigned gracefully when ctx is cancelled so another watcher can take
+// over without waiting for its lease to expire.
+func (d *Daemon) Run(ctx context.Context) error {
+	var campaignDone chan error
+	if d.cfg.Coordinator != nil {
+		go d.watchLeader(ctx)
+
+		campaignDone = make(chan error, 1)
+		go func() {
+			slog.Info(fmt.Sprintf("campaigning for leadership of elastic ip '%v'", d.cfg.EIP))
+			campaignDone <- d.cfg.Coordinator.Campaign(ctx)
+		}()
+
+		defer func() {
+			if !d.cfg.Coordinator.IsLeader() {
+				return
+			}
+			resignCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := d.cfg.Coordinator.Resign(resignCtx); err != nil {
+				slog.Error(fmt.Sprintf("could not resign leadership: %v", err))
+			}
+		}()
+	}
+
+	if d.cfg.StatusAddr != "" {
+		srv := &http.Server{Addr: d.cfg.StatusAddr, Handler: d.statusHandler()}
+		go func() {
+			if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error(fmt.Sprintf("status endpoint failed: %v", err))
+			}
+		}()
+		go func() {
+			<-ctx.Done()
+			_ = srv.Close()
+		}()
+	}
+
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	// reconcile once on startup instead of waiting for the first tick
+	d.tick(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-campaignDone:
+			campaignDone = nil
+			if err != nil {
+				slog.Error(fmt.Sprintf("campaign for leadership failed: %v", err))
+				continue
+			}
+			slog.Info(fmt.Sprintf("elected leader for elastic ip '%v'", d.cfg.EIP))
+		case <-ticker.C:
+			d.tick(ctx)
+		}
+	}
+}
+
+// watchLeader keeps currentLeader up to date via the coordinator's Observe
+// stream, so a follower's /status reports who currently holds leadership
+// while it waits its turn instead of only reporting its own campaign state.
+func (d *Daemon) watchLeader(ctx context.Context) {
+	for leader := range d.cfg.Coordinator.Observe(ctx) {
+		d.mu.Lock()
+		d.currentLeader = leader
+		d.mu.Unlock()
+	}
+}
+
+func (d *Daemon) tick(ctx context.Context) {
+	if d.cfg.Coordinator != nil && !d.cfg.Coordinator.IsLeader() {
+		slog.Info("not the leader, skipping reconciliation")
+		return
+	}
+
+	eipService := compute.NewElasticIPService(d.client)
+
+	elasticIP, err := findElasticIPID(ctx, eipService, d.cfg.EIP)
+	if err != nil {
+		slog.Error(fmt.Sprintf("could not look up elastic ip '%v': %v", d.cfg.EIP, err))
+		d.setProbeResult(false, err)
+		return
+	}
+
+	// keep every peer's fall/rise hysteresis warm between failovers, so
+	// HealthyPolicy's threshold reflects accumulated history rather than a
+	// single just-in-time probe taken only once a failover is triggered.
+	d.probePeers(ctx, elasticIP.PrivateIP)
+
+	healthy := d.checker.Check(ctx, elasticIP.PrivateIP)
+	_, lastErr, lastAt := d.checker.LastResult()
+	recordLastProbe(d.cfg.Group, lastAt)
+
+	d.mu.Lock()
+	d.lastProbeOK = healthy
+	d.lastProbeErr = lastErr
+	d.lastProbeAt = lastAt
+	if healthy {
+		d.state = StatePrimary
+	} else {
+		d.state = StateStandby
+	}
+	d.mu.Unlock()
+
+	if healthy {
+		recordFailover(d.cfg.Group, FailoverResultSkippedHealth)
+		return
+	}
+
+	slog.Warn(fmt.Sprintf(
+		"health check for elastic ip '%v' attached to instance '%v' failed %v consecutive times, failing over",
+		elasticIP.PublicIP,
+		elasticIP.Attachment.Name,
+		d.cfg.Health.FailureThreshold,
+	))
+
+	if err := performFailover(
+		ctx, d.client, d.cfg.Group, d.cfg.EIP, d.cfg.PeerIPs, d.cfg.Selector, d.peerHealth,
+		d.cfg.Announce, d.cfg.EIPv6, FailoverReasonHealthCheck, d.cfg.Notifier,
+	); err != nil {
+		slog.Error(fmt.Sprintf("failover failed: %v", err))
+		return
+	}
+
+	d.mu.Lock()
+	d.lastFailover = time.Now()
+	d.state = StateUnknown
+	d.mu.Unlock()
+
+	// the health checker was tracking the now-abandoned instance; reset it
+	// so the newly attached target starts with a clean slate.
+	checker, err := NewHealthChecker(d.cfg.Health)
+	if err != nil {
+		slog.Error(fmt.Sprintf("could not reset health checker: %v", err))
+		return
+	}
+	d.checker = checker
+}
+
+// probePeers checks every configured peer except the current holder, so
+// d.peerHealth's per-peer HealthChecker accumulates fall/rise state on the
+// same cadence as the primary probe instead of only being consulted once a
+// failover is already underway.
+func (d *Daemon) probePeers(ctx context.Context, currentHolderIP string) {
+	for _, ip := range d.cfg.PeerIPs {
+		if ip == currentHolderIP {
+			continue
+		}
+		if _, err := d.peerHealth.Check(ctx, ip); err != nil {
+			slog.Error(fmt.Sprintf("could not probe peer '%v': %v", ip, err))
+		}
+	}
+}
+
+func (d *Daemon) setProbeResult(ok bool, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.lastProbeOK = ok
+	d.lastProbeErr = err
+	d.lastProbeAt = time.Now()
+	d.state = StateUnknown
+}
+
+type daemonStatus struct {
+	State         State     `json:"state"`
+	LastProbeOK   bool      `json:"last_probe_ok"`
+	LastProbeErr  string    `json:"last_probe_error,omitempty"`
+	LastProbeAt   time.Time `json:"last_probe_at"`
+	LastFailover  time.Time `json:"last_failover,omitempty"`
+	CurrentLeader string    `json:"current_leader,omitempty"`
+}
+
+func (d *Daemon) statusHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		d.mu.Lock()
+		status := daemonStatus{
+			State:         d.state,
+			LastProbeOK:   d.lastProbeOK,
+			LastProbeAt:   d.lastProbeAt,
+			LastFailover:  d.lastFailover,
+			CurrentLeader: d.currentLeader,
+		}
+		if d.lastProbeErr != nil {
+			status.LastProbeErr = d.lastProbeErr.Error()
+		}
+		d.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(status)
+	})
+	return mux
+}
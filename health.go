@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// ProbeType identifies the transport used to determine whether a target is
+// still reachable.
+type ProbeType string
+
+const (
+	ProbeTypeTCP  ProbeType = "tcp"
+	ProbeTypeHTTP ProbeType = "http"
+	ProbeTypeICMP ProbeType = "icmp"
+)
+
+// Prober performs a single reachability check against target and reports
+// whether it succeeded.
+type Prober interface {
+	Probe(ctx context.Context, target string) error
+}
+
+// TCPProbe succeeds if a TCP connection to target:Port can be established
+// within the configured timeout.
+type TCPProbe struct {
+	Port    int
+	Timeout time.Duration
+}
+
+func (p TCPProbe) Probe(ctx context.Context, target string) error {
+	d := net.Dialer{Timeout: p.Timeout}
+	conn, err := d.DialContext(ctx, "tcp", fmt.Sprintf("%s:%d", target, p.Port))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// HTTPProbe succeeds if a GET request against target returns ExpectedStatus.
+type HTTPProbe struct {
+	Scheme         string
+	Port           int
+	Path           string
+	ExpectedStatus int
+	Timeout        time.Duration
+}
+
+func (p HTTPProbe) Probe(ctx context.Context, target string) error {
+	scheme := p.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+
+	url := fmt.Sprintf("%s://%s:%d%s", scheme, target, p.Port, p.Path)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	client := http.Client{Timeout: p.Timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != p.ExpectedStatus {
+		return fmt.Errorf("unexpected status code: got %v, want %v", resp.StatusCode, p.ExpectedStatus)
+	}
+
+	return nil
+}
+
+// ICMPProbe succeeds if target answers an ICMP echo request. It shells out
+// to the system `ping` binary instead of opening a raw socket, so it works
+// without CAP_NET_RAW.
+type ICMPProbe struct {
+	Timeout time.Duration
+}
+
+func (p ICMPProbe) Probe(ctx context.Context, target string) error {
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = time.Second
+	}
+
+	cmd := exec.CommandContext(ctx, "ping", "-c", "1", "-W", fmt.Sprintf("%d", int(timeout.Seconds())), target)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("icmp probe failed: %w", err)
+	}
+	return nil
+}
+
+// HealthCheckConfig describes how a target is probed and how many
+// consecutive results are required before the health state flips, mirroring
+// keepalived's fall/rise semantics. Probe cadence itself is not part of this
+// config; it is driven by the daemon's own poll interval (DaemonConfig.PollInterval).
+type HealthCheckConfig struct {
+	Type ProbeType
+
+	Timeout time.Duration
+
+	// FailureThreshold is the number of consecutive failed probes required
+	// to mark a healthy target as down ("fall").
+	FailureThreshold int
+	// SuccessThreshold is the number of consecutive successful probes
+	// required to mark a down target as healthy again ("rise").
+	SuccessThreshold int
+
+	TCP  TCPProbe
+	HTTP HTTPProbe
+	ICMP ICMPProbe
+}
+
+// validate checks that cfg is well-formed enough to actually probe with, so
+// a bad config (an unknown probe type, or a zero timeout/threshold) is
+// rejected by the caller instead of panicking inside NewDaemon or silently
+// manufacturing guaranteed spurious failovers: a zero timeout hands every
+// probe an already-expired context, so every probe "fails" regardless of
+// target health.
+func (cfg HealthCheckConfig) validate() error {
+	if cfg.Timeout <= 0 {
+		return fmt.Errorf("health timeout must be greater than zero")
+	}
+	if cfg.FailureThreshold <= 0 {
+		return fmt.Errorf("health fail threshold must be greater than zero")
+	}
+	if cfg.SuccessThreshold <= 0 {
+		return fmt.Errorf("health success threshold must be greater than zero")
+	}
+	if _, err := newProber(cfg); err != nil {
+		return fmt.Errorf("invalid health check: %w", err)
+	}
+	return nil
+}
+
+func newProber(cfg HealthCheckConfig) (Prober, error) {
+	switch cfg.Type {
+	case ProbeTypeTCP:
+		p := cfg.TCP
+		p.Timeout = cfg.Timeout
+		return p, nil
+	case ProbeTypeHTTP:
+		p := cfg.HTTP
+		p.Timeout = cfg.Timeout
+		return p, nil
+	case ProbeTypeICMP:
+		p := cfg.ICMP
+		p.Timeout = cfg.Timeout
+		return p, nil
+	default:
+		return nil, fmt.Errorf("unknown probe type: %v", cfg.Type)
+	}
+}
+
+// HealthChecker tracks consecutive probe results for a single target and
+// applies hysteresis so a flapping target does not flip the reported health
+// state on every probe.
+type HealthChecker struct {
+	cfg    HealthCheckConfig
+	prober Prober
+
+	healthy              bool
+	consecutiveFailures  int
+	consecutiveSuccesses int
+
+	lastErr error
+	lastAt  time.Time
+}
+
+func NewHealthChecker(cfg HealthCheckConfig) (*HealthChecker, error) {
+	prober, err := newProber(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &HealthChecker{
+		cfg:     cfg,
+		prober:  prober,
+		healthy: true,
+	}, nil
+}
+
+// Check runs a single probe against target and returns the health state
+// after hysteresis has been applied. The returned bool only changes once
+// the configured threshold of consecutive results has been observed.
+func (h *HealthChecker) Check(ctx context.Context, target string) bool {
+	probeCtx, cancel := context.WithTimeout(ctx, h.cfg.Timeout)
+	defer cancel()
+
+	err := h.prober.Probe(probeCtx, target)
+	h.lastErr = err
+	h.lastAt = time.Now()
+
+	if err != nil {
+		h.consecutiveFailures++
+		h.consecutiveSuccesses = 0
+
+		if h.healthy && h.consecutiveFailures >= h.cfg.FailureThreshold {
+			h.healthy = false
+		}
+
+		return h.healthy
+	}
+
+	h.consecutiveSuccesses++
+	h.consecutiveFailures = 0
+
+	if !h.healthy && h.consecutiveSuccesses >= h.cfg.SuccessThreshold {
+		h.healthy = true
+	}
+
+	return h.healthy
+}
+
+// LastResult returns the outcome of the most recent probe along with the
+// hysteresis-adjusted health state.
+func (h *HealthChecker) LastResult() (healthy bool, err error, at time.Time) {
+	return h.healthy, h.lastErr, h.lastAt
+}
+
+// PeerHealthPool keeps one HealthChecker per peer IP alive across probes,
+// so fall/rise hysteresis accumulates over repeated checks instead of being
+// discarded and restarted from a fresh "healthy" state every time a peer is
+// checked, which would make a FailureThreshold greater than 1 unreachable.
+type PeerHealthPool struct {
+	cfg HealthCheckConfig
+
+	mu       sync.Mutex
+	checkers map[string]*HealthChecker
+}
+
+func NewPeerHealthPool(cfg HealthCheckConfig) *PeerHealthPool {
+	return &PeerHealthPool{cfg: cfg, checkers: map[string]*HealthChecker{}}
+}
+
+// Check runs a probe against ip using that peer's own HealthChecker,
+// creating one on first use, and returns the hysteresis-adjusted result.
+func (p *PeerHealthPool) Check(ctx context.Context, ip string) (bool, error) {
+	p.mu.Lock()
+	checker, ok := p.checkers[ip]
+	if !ok {
+		var err error
+		checker, err = NewHealthChecker(p.cfg)
+		if err != nil {
+			p.mu.Unlock()
+			return false, err
+		}
+		p.checkers[ip] = checker
+	}
+	p.mu.Unlock()
+
+	return checker.Check(ctx, ip), nil
+}
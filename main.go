@@ -10,31 +10,42 @@ import (
 	"log/slog"
 	"net"
 	"os"
+	"os/signal"
 	"slices"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 )
 
 var (
-	flagToken = ""
-	flagIPs   = ""
-	flagEIP   = ""
+	flagToken       = ""
+	flagIPs         = ""
+	flagEIP         = ""
+	flagWatch       = false
+	flagConfig      = ""
+	flagCheckConfig = false
 )
 
 func main() {
 	// init flags
 	flag.StringVar(&flagToken, "token", "", "MyFlow API token")
-	flag.StringVar(&flagEIP, "eip", "", "High-Availability Elastic IP")
-	flag.StringVar(&flagIPs, "peers", "", "High-Availability Instance Private IPs (comma-separated)")
+	flag.StringVar(&flagEIP, "eip", "", "High-Availability Elastic IP (ignored if --config is set)")
+	flag.StringVar(&flagIPs, "peers", "", "High-Availability Instance Private IPs, comma-separated (ignored if --config is set)")
+	flag.BoolVar(&flagWatch, "watch", false, "keep running and only fail over when health checks fail")
+	flag.StringVar(&flagConfig, "config", "", "path to a YAML config declaring one or more HA groups")
+	flag.BoolVar(&flagCheckConfig, "check-config", false, "validate --config against the Flow API and exit without mutating anything (peer ips are checked for existence and same-zone attachment, but not subnet/VPC membership, which the Flow API does not expose)")
+	registerDaemonFlags()
+	registerCoordinatorFlags()
+	registerAnnounceFlags()
+	registerSelectorFlags()
+	registerMetricsFlags()
+	registerNotifyFlags()
 	flag.Parse()
 
 	// validate flags
 	token, err := checkFlagToken()
 	failOnErr(err)
-	haEIP, err := checkFlagEIP()
-	failOnErr(err)
-	haPeerIPs, err := checkFlagIPs()
-	failOnErr(err)
 
 	// init logging
 	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
@@ -42,14 +53,125 @@ func main() {
 
 	// init flow client
 	client := goclient.NewClient(goclient.WithToken(token))
-	eipService := compute.NewElasticIPService(client)
-	serverService := compute.NewServerService(client)
+
+	var cfg Config
+	if flagConfig != "" {
+		cfg, err = loadConfig(flagConfig)
+		failOnErr(err)
+	} else {
+		haEIP, err := checkFlagEIP()
+		failOnErr(err)
+		haPeerIPs, err := checkFlagIPs()
+		failOnErr(err)
+		cfg = configFromFlags(haEIP, haPeerIPs)
+
+		for _, group := range cfg.Groups {
+			failOnErr(group.validate())
+		}
+	}
+
+	if flagCheckConfig {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		failOnErr(checkConfig(ctx, client, cfg))
+		slog.Info("config is valid")
+		return
+	}
+
+	workers := make([]*GroupWorker, 0, len(cfg.Groups))
+	for _, group := range cfg.Groups {
+		workers = append(workers, NewGroupWorker(client, group))
+	}
+
+	if flagWatch {
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+
+		serveMetrics(ctx)
+
+		failOnErr(runDaemons(ctx, workers))
+		return
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
 	defer cancel()
 
-	elasticIP, err := findElasticIPID(ctx, eipService, haEIP)
-	failOnErr(err)
+	for _, worker := range workers {
+		failOnErr(worker.RunOnce(ctx))
+	}
+}
+
+// runDaemons starts one Daemon per group and waits for all of them to stop,
+// which happens once ctx is cancelled.
+func runDaemons(ctx context.Context, workers []*GroupWorker) error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(workers))
+
+	for i, worker := range workers {
+		daemon, err := worker.Daemon()
+		if err != nil {
+			return err
+		}
+
+		wg.Add(1)
+		go func(i int, d *Daemon) {
+			defer wg.Done()
+			errs[i] = d.Run(ctx)
+		}(i, daemon)
+	}
+
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// performFailover moves the High-Availability Elastic IP away from its
+// current attachment to the best available peer. It is the one-shot
+// behaviour invoked directly by main, and is reused by the daemon once a
+// health check trips. If announce is non-nil, it is invoked once the EIP is
+// attached to the new target so upstream routers and neighbours refresh
+// their ARP/NDP caches instead of blackholing traffic. Every attempt is
+// reported to notifier and recorded in the ha_flow_failovers_total metric,
+// whether it succeeds or not.
+func performFailover(
+	ctx context.Context,
+	client *goclient.Client,
+	group string,
+	haEIP string,
+	haPeerIPs []string,
+	selectorCfg SelectorConfig,
+	peerHealth *PeerHealthPool,
+	announce AnnounceHook,
+	eipv6 string,
+	reason FailoverReason,
+	notifier Notifier,
+) (err error) {
+	start := time.Now()
+	event := FailoverEvent{Group: group, EIP: haEIP, Reason: reason}
+
+	defer func() {
+		event.Duration = time.Since(start)
+		if err != nil {
+			event.Error = err.Error()
+			recordFailover(group, FailoverResultFailed)
+		} else {
+			recordFailover(group, FailoverResultPerformed)
+		}
+		if notifyErr := notifier.Notify(ctx, event); notifyErr != nil {
+			slog.Error(fmt.Sprintf("could not send failover notification: %v", notifyErr))
+		}
+	}()
+
+	eipService := compute.NewElasticIPService(client)
+	serverService := compute.NewServerService(client)
+
+	elasticIP, err := observeAPICall("List", func() (compute.ElasticIP, error) {
+		return findElasticIPID(ctx, eipService, haEIP)
+	})
+	if err != nil {
+		return err
+	}
+	event.OldHolder = elasticIP.Attachment.Name
 	slog.Info(fmt.Sprintf(
 		"found elastic ip '%v' attached to instance '%v' with id '%v'",
 		elasticIP.PublicIP,
@@ -59,7 +181,11 @@ func main() {
 
 	// detach from current
 	failedInstanceEIPService := compute.NewServerElasticIPService(client, elasticIP.Attachment.ID)
-	failOnErr(failedInstanceEIPService.Detach(ctx, elasticIP.ID))
+	if err := observeAPICallErr("Detach", func() error {
+		return failedInstanceEIPService.Detach(ctx, elasticIP.ID)
+	}); err != nil {
+		return err
+	}
 	slog.Info(fmt.Sprintf("detached elastic ip from instance '%v' with id '%v'",
 		elasticIP.Attachment.Name,
 		elasticIP.Attachment.ID,
@@ -69,8 +195,11 @@ func main() {
 	candidatePrivateIPs := filterPeers(haPeerIPs, elasticIP.PrivateIP)
 
 	// choose instance using peer list
-	target, err := pickFailOverTarget(ctx, serverService, candidatePrivateIPs)
-	failOnErr(err)
+	target, err := pickFailOverTarget(ctx, serverService, candidatePrivateIPs, elasticIP.Attachment.ID, selectorCfg, peerHealth)
+	if err != nil {
+		return err
+	}
+	event.NewHolder = target.InstanceName
 	slog.Info(fmt.Sprintf(
 		"picked target instance '%v' with id '%v' for failover",
 		target.InstanceName,
@@ -78,16 +207,21 @@ func main() {
 	))
 
 	// detach existing EIPs if there are any attached on the target network interface
-	err = prepareTarget(ctx, target, compute.NewServerElasticIPService(client, target.InstanceID))
-	failOnErr(err)
+	if err := prepareTarget(ctx, target, compute.NewServerElasticIPService(client, target.InstanceID)); err != nil {
+		return err
+	}
 
 	// attach the HA EIP to the target network interface
 	targetInstanceEIPService := compute.NewServerElasticIPService(client, target.InstanceID)
-	_, err = targetInstanceEIPService.Attach(ctx, compute.ElasticIPAttach{
-		ElasticIPID:        elasticIP.ID,
-		NetworkInterfaceID: target.NetworkInterfaceID,
+	_, err = observeAPICall("Attach", func() (compute.ElasticIP, error) {
+		return targetInstanceEIPService.Attach(ctx, compute.ElasticIPAttach{
+			ElasticIPID:        elasticIP.ID,
+			NetworkInterfaceID: target.NetworkInterfaceID,
+		})
 	})
-	failOnErr(err)
+	if err != nil {
+		return err
+	}
 	slog.Info(fmt.Sprintf(
 		"attached High-Availability elastic ip '%v' to target instance '%v' with id '%v' on network interface with id '%v'",
 		elasticIP.PublicIP,
@@ -95,6 +229,17 @@ func main() {
 		target.InstanceID,
 		target.NetworkInterfaceID,
 	))
+	recordEIPHolder(group, elasticIP.PublicIP, target.InstanceID, target.InstanceName)
+
+	if announce != nil {
+		if err := announce.Announce(ctx, target, elasticIP.PublicIP, eipv6); err != nil {
+			// a missed gratuitous ARP degrades failover time but the EIP
+			// move itself already succeeded, so this is logged, not fatal.
+			slog.Error(fmt.Sprintf("could not announce new attachment: %v", err))
+		}
+	}
+
+	return nil
 }
 
 func checkFlagToken() (string, error) {
@@ -166,6 +311,7 @@ type Target struct {
 	InstanceID         int
 	InstanceName       string
 	NetworkInterfaceID int
+	PrivateIP          string
 	AttachedEIP        compute.ElasticIP
 }
 
@@ -173,20 +319,34 @@ func pickFailOverTarget(
 	ctx context.Context,
 	service compute.ServerService,
 	peersPrivateIPs []string,
+	failedInstanceID int,
+	selectorCfg SelectorConfig,
+	peerHealth *PeerHealthPool,
 ) (Target, error) {
+	listStart := time.Now()
 	instances, err := service.List(ctx, goclient.Cursor{NoFilter: 1})
+	metricAPICallDuration.WithLabelValues("List").Observe(time.Since(listStart).Seconds())
 	if err != nil {
 		return Target{}, err
 	}
 
+	failedZoneID := 0
+	var candidates []Candidate
+
 	for _, instance := range instances.Items {
+		if instance.ID == failedInstanceID {
+			failedZoneID = instance.Zone.ID
+		}
+
 		// skip instances which are not available
 		if instance.Status.ID != compute.ServerStatusRunning {
 			continue
 		}
 
 		// find instance in the same network which has a private IP contained in the peer list
+		nicListStart := time.Now()
 		networkInterfaces, err := service.NetworkInterfaces(instance.ID).List(ctx, goclient.Cursor{NoFilter: 1})
+		metricAPICallDuration.WithLabelValues("NetworkInterfaces.List").Observe(time.Since(nicListStart).Seconds())
 		if err != nil {
 			return Target{}, err
 		}
@@ -197,17 +357,25 @@ func pickFailOverTarget(
 			})
 
 			if inPeers {
-				return Target{
+				candidates = append(candidates, Candidate{
 					InstanceID:         instance.ID,
 					InstanceName:       instance.Name,
+					ZoneID:             instance.Zone.ID,
 					NetworkInterfaceID: networkInterface.ID,
+					PrivateIP:          networkInterface.PrivateIP,
 					AttachedEIP:        networkInterface.AttachedElasticIP,
-				}, nil
+				})
 			}
 		}
 	}
 
-	return Target{}, errors.New("no available instance found")
+	selector := newDefaultSelector(selectorCfg, peerHealth, failedZoneID)
+	chosen, err := selector.Select(ctx, candidates)
+	if err != nil {
+		return Target{}, err
+	}
+
+	return chosen.target(), nil
 }
 
 func prepareTarget(ctx context.Context, target Target, service compute.ServerElasticIPService) error {
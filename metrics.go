@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	flagMetricsAddr = ""
+)
+
+func registerMetricsFlags() {
+	flag.StringVar(&flagMetricsAddr, "metrics-addr", "", "address to serve Prometheus metrics on, e.g. :9090 (disabled if empty)")
+}
+
+// serveMetrics starts the Prometheus metrics endpoint if --metrics-addr is
+// set, and stops it once ctx is cancelled.
+func serveMetrics(ctx context.Context) {
+	if flagMetricsAddr == "" {
+		return
+	}
+
+	srv := &http.Server{Addr: flagMetricsAddr, Handler: promhttp.Handler()}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			slog.Error(fmt.Sprintf("metrics endpoint failed: %v", err))
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		_ = srv.Close()
+	}()
+}
+
+var (
+	metricEIPHolder = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ha_flow_eip_holder",
+		Help: "1 for the instance currently holding a group's HA Elastic IP, labelled by group, instance id and instance name.",
+	}, []string{"group", "eip", "instance_id", "instance_name"})
+
+	metricFailoversTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ha_flow_failovers_total",
+		Help: "Count of failovers by group and result (performed, failed, skipped_due_to_health).",
+	}, []string{"group", "result"})
+
+	metricAPICallDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ha_flow_api_call_duration_seconds",
+		Help:    "Time spent in each Flow API call.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"call"})
+
+	metricLastProbeTimestamp = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ha_flow_last_probe_timestamp_seconds",
+		Help: "Unix timestamp of the last health probe per group.",
+	}, []string{"group"})
+)
+
+// observeAPICall times fn and records it under the ha_flow_api_call_duration_seconds
+// histogram, labelled by call.
+func observeAPICall[T any](call string, fn func() (T, error)) (T, error) {
+	start := time.Now()
+	result, err := fn()
+	metricAPICallDuration.WithLabelValues(call).Observe(time.Since(start).Seconds())
+	return result, err
+}
+
+// observeAPICallErr is the observeAPICall variant for calls that only return
+// an error, such as Detach.
+func observeAPICallErr(call string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	metricAPICallDuration.WithLabelValues(call).Observe(time.Since(start).Seconds())
+	return err
+}
+
+var lastEIPHolderLabels sync.Map // group -> []string
+
+// recordEIPHolder sets the current holder gauge for group and clears the
+// series left by that group's previous holder, so a stale instance does
+// not linger at value 1 forever.
+func recordEIPHolder(group, eip string, instanceID int, instanceName string) {
+	if prev, ok := lastEIPHolderLabels.Load(group); ok {
+		metricEIPHolder.DeleteLabelValues(prev.([]string)...)
+	}
+
+	labels := []string{group, eip, fmt.Sprintf("%d", instanceID), instanceName}
+	metricEIPHolder.WithLabelValues(labels...).Set(1)
+	lastEIPHolderLabels.Store(group, labels)
+}
+
+func recordLastProbe(group string, at time.Time) {
+	metricLastProbeTimestamp.WithLabelValues(group).Set(float64(at.Unix()))
+}
+
+const (
+	FailoverResultPerformed     = "performed"
+	FailoverResultFailed        = "failed"
+	FailoverResultSkippedHealth = "skipped_due_to_health"
+)
+
+func recordFailover(group, result string) {
+	metricFailoversTotal.WithLabelValues(group, result).Inc()
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// FailoverReason identifies why a failover was attempted, surfaced to
+// webhook subscribers so they don't have to reconstruct it from logs.
+type FailoverReason string
+
+const (
+	FailoverReasonHealthCheck FailoverReason = "health-check-failure"
+	FailoverReasonManual      FailoverReason = "manual"
+	FailoverReasonStartup     FailoverReason = "startup-reconciliation"
+)
+
+// FailoverEvent describes one failover attempt, successful or not.
+type FailoverEvent struct {
+	Group     string         `json:"group"`
+	EIP       string         `json:"eip"`
+	OldHolder string         `json:"old_holder"`
+	NewHolder string         `json:"new_holder,omitempty"`
+	Reason    FailoverReason `json:"reason"`
+	Duration  time.Duration  `json:"duration"`
+	Error     string         `json:"error,omitempty"`
+}
+
+// Notifier fires an outbound notification for a failover event. It must
+// not return an error that blocks the failover itself; callers only log
+// what it returns.
+type Notifier interface {
+	Notify(ctx context.Context, event FailoverEvent) error
+}
+
+type noopNotifier struct{}
+
+func (noopNotifier) Notify(ctx context.Context, event FailoverEvent) error { return nil }
+
+// NotificationPreset selects the JSON shape POSTed to the webhook.
+type NotificationPreset string
+
+const (
+	// NotificationPresetGeneric POSTs the FailoverEvent as-is.
+	NotificationPresetGeneric NotificationPreset = "generic"
+	// NotificationPresetSlack POSTs a Slack-compatible {"text": "..."} payload.
+	NotificationPresetSlack NotificationPreset = "slack"
+)
+
+// NotificationConfig configures the webhook notifier.
+type NotificationConfig struct {
+	WebhookURL string
+	Preset     NotificationPreset
+	Timeout    time.Duration
+}
+
+// WebhookNotifier POSTs a JSON payload describing the failover to a
+// generic webhook, so operators can wire ha-flow into their existing
+// alerting stack without writing a custom integration.
+type WebhookNotifier struct {
+	cfg NotificationConfig
+}
+
+func NewWebhookNotifier(cfg NotificationConfig) *WebhookNotifier {
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	if cfg.Preset == "" {
+		cfg.Preset = NotificationPresetGeneric
+	}
+	return &WebhookNotifier{cfg: cfg}
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, event FailoverEvent) error {
+	payload, err := n.payload(event)
+	if err != nil {
+		return fmt.Errorf("build webhook payload: %w", err)
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, n.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, n.cfg.WebhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %v", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func (n *WebhookNotifier) payload(event FailoverEvent) ([]byte, error) {
+	switch n.cfg.Preset {
+	case NotificationPresetSlack:
+		text := fmt.Sprintf(
+			"ha-flow: failover for group `%s` (eip `%s`): `%s` -> `%s` (reason: %s, took %s)",
+			event.Group, event.EIP, event.OldHolder, event.NewHolder, event.Reason, event.Duration,
+		)
+		if event.Error != "" {
+			text += fmt.Sprintf("\nfailed: %s", event.Error)
+		}
+		return json.Marshal(struct {
+			Text string `json:"text"`
+		}{Text: text})
+	case NotificationPresetGeneric, "":
+		return json.Marshal(event)
+	default:
+		return nil, fmt.Errorf("unknown notification preset: %v", n.cfg.Preset)
+	}
+}
+
+var (
+	flagNotifyWebhookURL = ""
+	flagNotifyPreset     = "generic"
+	flagNotifyTimeout    = 5 * time.Second
+)
+
+func registerNotifyFlags() {
+	flag.StringVar(&flagNotifyWebhookURL, "notify-webhook-url", "", "webhook URL to POST failover events to (disabled if empty)")
+	flag.StringVar(&flagNotifyPreset, "notify-preset", "generic", "webhook payload shape (generic, slack)")
+	flag.DurationVar(&flagNotifyTimeout, "notify-timeout", 5*time.Second, "timeout for the webhook request")
+}
+
+func notifierFromFlags() Notifier {
+	if flagNotifyWebhookURL == "" {
+		return noopNotifier{}
+	}
+	return NewWebhookNotifier(NotificationConfig{
+		WebhookURL: flagNotifyWebhookURL,
+		Preset:     NotificationPreset(flagNotifyPreset),
+		Timeout:    flagNotifyTimeout,
+	})
+}
+
+// NotificationConfigYAML is the YAML representation of NotificationConfig.
+type NotificationConfigYAML struct {
+	WebhookURL string             `yaml:"webhook_url"`
+	Preset     NotificationPreset `yaml:"preset,omitempty"`
+	Timeout    Duration           `yaml:"timeout,omitempty"`
+}
+
+func (n *NotificationConfigYAML) toNotifier() Notifier {
+	if n == nil || n.WebhookURL == "" {
+		return notifierFromFlags()
+	}
+	return NewWebhookNotifier(NotificationConfig{
+		WebhookURL: n.WebhookURL,
+		Preset:     n.Preset,
+		Timeout:    n.Timeout.Duration(),
+	})
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flowswiss/goclient/compute"
+)
+
+// Candidate is a peer instance eligible to receive the HA Elastic IP,
+// together with everything the scoring policies need to judge it.
+type Candidate struct {
+	InstanceID         int
+	InstanceName       string
+	ZoneID             int
+	NetworkInterfaceID int
+	PrivateIP          string
+	AttachedEIP        compute.ElasticIP
+}
+
+func (c Candidate) target() Target {
+	return Target{
+		InstanceID:         c.InstanceID,
+		InstanceName:       c.InstanceName,
+		NetworkInterfaceID: c.NetworkInterfaceID,
+		PrivateIP:          c.PrivateIP,
+		AttachedEIP:        c.AttachedEIP,
+	}
+}
+
+// PolicyScore is one policy's contribution to a candidate's total score,
+// kept around so the selection can be logged for post-mortems.
+type PolicyScore struct {
+	Policy string
+	Score  float64
+	Reason string
+}
+
+// Policy scores a single candidate. Positive scores make a candidate more
+// attractive, negative scores less so; policies are free to return 0 when
+// they have no opinion about a candidate.
+type Policy interface {
+	Name() string
+	Score(ctx context.Context, candidate Candidate) (score float64, reason string)
+}
+
+// PriorityPolicy scores candidates by an operator-assigned priority per
+// peer private IP, highest priority first.
+type PriorityPolicy struct {
+	// Priorities maps a peer's private IP to its configured priority.
+	// Peers not listed score 0.
+	Priorities map[string]int
+}
+
+func (p PriorityPolicy) Name() string { return "priority" }
+
+func (p PriorityPolicy) Score(ctx context.Context, candidate Candidate) (float64, string) {
+	priority, ok := p.Priorities[candidate.PrivateIP]
+	if !ok {
+		return 0, "no explicit priority configured"
+	}
+	return float64(priority), fmt.Sprintf("configured priority %d", priority)
+}
+
+// NotHoldingEIPPolicy prefers candidates that are not already holding
+// another Elastic IP on the target network interface, since attaching one
+// there would require detaching it first.
+type NotHoldingEIPPolicy struct {
+	Weight float64
+}
+
+func (p NotHoldingEIPPolicy) Name() string { return "not-holding-eip" }
+
+func (p NotHoldingEIPPolicy) Score(ctx context.Context, candidate Candidate) (float64, string) {
+	if candidate.AttachedEIP.PublicIP == "" {
+		return p.Weight, "network interface is free"
+	}
+	return 0, fmt.Sprintf("already holding elastic ip '%v'", candidate.AttachedEIP.PublicIP)
+}
+
+// SameZonePolicy prefers candidates in the same availability zone as the
+// instance that just failed, since that is usually where the rest of the
+// workload's dependencies (storage, internal load balancers) live.
+type SameZonePolicy struct {
+	FailedZoneID int
+	Weight       float64
+}
+
+func (p SameZonePolicy) Name() string { return "same-zone" }
+
+func (p SameZonePolicy) Score(ctx context.Context, candidate Candidate) (float64, string) {
+	if p.FailedZoneID == 0 || candidate.ZoneID != p.FailedZoneID {
+		return 0, "different availability zone than the failed instance"
+	}
+	return p.Weight, "same availability zone as the failed instance"
+}
+
+// HealthyPolicy prefers candidates whose health check passes right now by
+// adding Weight to their score. It does not exclude unhealthy candidates: one
+// can still be chosen if the other policies score it higher, or if no
+// candidate is healthy. Pool is shared across selections so each candidate's fall/rise hysteresis
+// accumulates over repeated checks instead of starting fresh every time.
+type HealthyPolicy struct {
+	Pool   *PeerHealthPool
+	Weight float64
+}
+
+func (p HealthyPolicy) Name() string { return "healthy" }
+
+func (p HealthyPolicy) Score(ctx context.Context, candidate Candidate) (float64, string) {
+	healthy, err := p.Pool.Check(ctx, candidate.PrivateIP)
+	if err != nil {
+		return 0, fmt.Sprintf("could not build health checker: %v", err)
+	}
+
+	if !healthy {
+		return 0, "health check failed"
+	}
+	return p.Weight, "health check passed"
+}
+
+// LoadPolicy prefers candidates reporting lower recently-observed load via
+// a user-supplied HTTP metrics endpoint. URLTemplate must contain exactly
+// one "%s", which is replaced with the candidate's private IP.
+type LoadPolicy struct {
+	URLTemplate string
+	Weight      float64
+	Timeout     time.Duration
+}
+
+type loadMetricsResponse struct {
+	Load float64 `json:"load"`
+}
+
+func (p LoadPolicy) Name() string { return "load" }
+
+func (p LoadPolicy) Score(ctx context.Context, candidate Candidate) (float64, string) {
+	url := strings.Replace(p.URLTemplate, "%s", candidate.PrivateIP, 1)
+
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, fmt.Sprintf("could not build metrics request: %v", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, fmt.Sprintf("metrics endpoint unreachable: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var metrics loadMetricsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&metrics); err != nil {
+		return 0, fmt.Sprintf("could not parse metrics response: %v", err)
+	}
+
+	// lower load is better, so invert it into a score
+	score := p.Weight / (1 + metrics.Load)
+	return score, fmt.Sprintf("reported load %.2f", metrics.Load)
+}
+
+// SelectorConfig controls which policies the default composed
+// TargetSelector applies, and how heavily each one is weighted.
+type SelectorConfig struct {
+	Priorities map[string]int
+
+	PreferUnattached float64
+	PreferSameZone   float64
+	PreferHealthy    float64
+	PreferLowLoad    float64
+
+	LoadMetricsURLTemplate string
+}
+
+// newDefaultSelector builds the composed TargetSelector described by cfg:
+// explicit priority, then "not already holding an EIP", "same availability
+// zone as the failed instance", "health check passes now", and "lowest
+// recently observed load", in that order. peerHealth is shared with the
+// caller so HealthyPolicy's hysteresis survives across selections.
+func newDefaultSelector(cfg SelectorConfig, peerHealth *PeerHealthPool, failedZoneID int) *PolicySelector {
+	policies := []Policy{
+		PriorityPolicy{Priorities: cfg.Priorities},
+	}
+
+	if cfg.PreferUnattached > 0 {
+		policies = append(policies, NotHoldingEIPPolicy{Weight: cfg.PreferUnattached})
+	}
+	if cfg.PreferSameZone > 0 {
+		policies = append(policies, SameZonePolicy{FailedZoneID: failedZoneID, Weight: cfg.PreferSameZone})
+	}
+	if cfg.PreferHealthy > 0 {
+		policies = append(policies, HealthyPolicy{Pool: peerHealth, Weight: cfg.PreferHealthy})
+	}
+	if cfg.PreferLowLoad > 0 && cfg.LoadMetricsURLTemplate != "" {
+		policies = append(policies, LoadPolicy{URLTemplate: cfg.LoadMetricsURLTemplate, Weight: cfg.PreferLowLoad})
+	}
+
+	return NewPolicySelector(policies...)
+}
+
+var (
+	flagSelectPriorities       = priorityMap{}
+	flagSelectPreferUnattached = 1.0
+	flagSelectPreferSameZone   = 0.0
+	flagSelectPreferHealthy    = 0.0
+	flagSelectPreferLowLoad    = 0.0
+	flagSelectLoadMetricsURL   = ""
+)
+
+// priorityMap implements flag.Value so --select-priority can be repeated as
+// --select-priority 10.0.0.1=100 to assign explicit per-peer priorities.
+type priorityMap map[string]int
+
+func (m priorityMap) String() string {
+	parts := make([]string, 0, len(m))
+	for ip, priority := range m {
+		parts = append(parts, fmt.Sprintf("%s=%d", ip, priority))
+	}
+	return strings.Join(parts, ",")
+}
+
+func (m priorityMap) Set(value string) error {
+	ip, priorityStr, ok := strings.Cut(value, "=")
+	if !ok {
+		return fmt.Errorf("expected ip=priority, got %q", value)
+	}
+
+	priority, err := strconv.Atoi(priorityStr)
+	if err != nil {
+		return fmt.Errorf("invalid priority %q: %w", priorityStr, err)
+	}
+
+	m[ip] = priority
+	return nil
+}
+
+func registerSelectorFlags() {
+	flag.Var(flagSelectPriorities, "select-priority", "explicit priority for a peer, as ip=priority (repeatable)")
+	flag.Float64Var(&flagSelectPreferUnattached, "select-prefer-unattached", 1, "score weight for peers not already holding another elastic ip")
+	flag.Float64Var(&flagSelectPreferSameZone, "select-prefer-same-zone", 0, "score weight for peers in the same availability zone as the failed instance")
+	flag.Float64Var(&flagSelectPreferHealthy, "select-prefer-healthy", 0, "score weight for peers whose health check passes right now")
+	flag.Float64Var(&flagSelectPreferLowLoad, "select-prefer-low-load", 0, "score weight for peers reporting low load on --select-load-metrics-url")
+	flag.StringVar(&flagSelectLoadMetricsURL, "select-load-metrics-url", "", "URL template (with %s for the peer's private ip) returning {\"load\": n}")
+}
+
+func selectorConfigFromFlags() SelectorConfig {
+	return SelectorConfig{
+		Priorities:             flagSelectPriorities,
+		PreferUnattached:       flagSelectPreferUnattached,
+		PreferSameZone:         flagSelectPreferSameZone,
+		PreferHealthy:          flagSelectPreferHealthy,
+		PreferLowLoad:          flagSelectPreferLowLoad,
+		LoadMetricsURLTemplate: flagSelectLoadMetricsURL,
+	}
+}
+
+// TargetSelector picks the best candidate to receive the HA Elastic IP.
+type TargetSelector interface {
+	Select(ctx context.Context, candidates []Candidate) (Candidate, error)
+}
+
+// PolicySelector scores every candidate with a chain of policies and picks
+// the highest total score, breaking ties deterministically by instance ID
+// so repeated runs converge on the same choice.
+type PolicySelector struct {
+	policies []Policy
+}
+
+func NewPolicySelector(policies ...Policy) *PolicySelector {
+	return &PolicySelector{policies: policies}
+}
+
+func (s *PolicySelector) Select(ctx context.Context, candidates []Candidate) (Candidate, error) {
+	if len(candidates) == 0 {
+		return Candidate{}, fmt.Errorf("no available instance found")
+	}
+
+	type scored struct {
+		candidate Candidate
+		total     float64
+		breakdown []PolicyScore
+	}
+
+	results := make([]scored, len(candidates))
+	for i, candidate := range candidates {
+		var total float64
+		breakdown := make([]PolicyScore, 0, len(s.policies))
+
+		for _, policy := range s.policies {
+			score, reason := policy.Score(ctx, candidate)
+			total += score
+			breakdown = append(breakdown, PolicyScore{Policy: policy.Name(), Score: score, Reason: reason})
+		}
+
+		results[i] = scored{candidate: candidate, total: total, breakdown: breakdown}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].total != results[j].total {
+			return results[i].total > results[j].total
+		}
+		return results[i].candidate.InstanceID < results[j].candidate.InstanceID
+	})
+
+	for _, r := range results {
+		var parts []string
+		for _, p := range r.breakdown {
+			parts = append(parts, fmt.Sprintf("%s=%.2f (%s)", p.Policy, p.Score, p.Reason))
+		}
+		slog.Info(fmt.Sprintf(
+			"candidate instance '%v' (%v) scored %.2f: %s",
+			r.candidate.InstanceName,
+			r.candidate.InstanceID,
+			r.total,
+			strings.Join(parts, ", "),
+		))
+	}
+
+	return results[0].candidate, nil
+}